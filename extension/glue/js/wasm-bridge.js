@@ -302,6 +302,29 @@ var DomDiffBridge = (function() {
     console.log('[Bridge] ================================================');
   }
 
+  // 截断报告字段（下标与 dom_tree_truncation_field 一致）
+  var TRUNCATION_REPORT_FIELDS = [
+    'originalNodes', 'originalBytes', 'retainedNodes', 'retainedBytes',
+    'truncatedTexts', 'depthPrunedNodes', 'subtreePrunedNodes', 'limitPrunedNodes'
+  ];
+
+  /**
+   * 超过默认上限时截断DOM树，返回截断报告（随快照元数据保存）
+   */
+  function truncateTree(treeId) {
+    if (wasm.dom_tree_truncate(treeId, 0, 0) === 0) {
+      throw new Error('Failed to truncate tree');
+    }
+
+    var report = {};
+    for (var i = 0; i < TRUNCATION_REPORT_FIELDS.length; i++) {
+      report[TRUNCATION_REPORT_FIELDS[i]] = wasm.dom_tree_truncation_field(treeId, i);
+    }
+    report.truncated = report.retainedNodes < report.originalNodes || report.truncatedTexts > 0;
+
+    return report;
+  }
+
   /**
    * 捕获完整DOM树
    */
@@ -327,6 +350,14 @@ var DomDiffBridge = (function() {
 
       var nodeList = [];
       var nodeCount = addDomNodeToWasm(treeId, bodyNode, null, nodeList);
+      var truncation = truncateTree(treeId);
+
+      if (truncation.truncated) {
+        // 只保留仍在WASM树中的节点，释放被截断节点的DOM引用
+        nodeList = nodeList.filter(function(node) {
+          return wasm.dom_tree_has_node(treeId, BigInt(node.id)) === 1;
+        });
+      }
 
       var duration = performance.now() - startTime;
       var wasmNodeCount = wasm.dom_tree_node_count(treeId);
@@ -337,6 +368,9 @@ var DomDiffBridge = (function() {
       console.log('[Bridge] JavaScript计数:', nodeCount, '个节点');
       console.log('[Bridge] WASM计数:', Number(wasmNodeCount), '个节点');
       console.log('[Bridge] 耗时:', duration.toFixed(2), 'ms');
+      if (truncation.truncated) {
+        console.warn('[Bridge] ⚠️ 页面超过上限已截断:', truncation);
+      }
       console.log('[Bridge] ================================================');
 
       currentTreeId = treeId;
@@ -345,7 +379,8 @@ var DomDiffBridge = (function() {
       return {
         treeId: Number(treeId),
         nodeCount: Number(wasmNodeCount),
-        duration: duration
+        duration: duration,
+        truncation: truncation
       };
 
     } catch (error) {
@@ -22,12 +22,31 @@ export interface DiffResult {
   duration: number;
 }
 
+/**
+ * 截断报告（随快照元数据保存）
+ */
+export interface TruncationReport {
+  originalNodes: number;
+  originalBytes: number;
+  retainedNodes: number;
+  retainedBytes: number;
+  truncatedTexts: number;
+  depthPrunedNodes: number;
+  subtreePrunedNodes: number;
+  limitPrunedNodes: number;
+  truncated: boolean;
+}
+
 export interface DomCaptureResult {
   treeId: number;
   nodeCount: number;
   duration: number;
+  truncation: TruncationReport;
 }
 
+/** 截断报告字段数（dom_tree_truncation_field 的下标范围） */
+const TRUNCATION_REPORT_LEN = 8;
+
 class DomDiffBridge {
   private wasm: any = null;
   private loaded: boolean = false;
@@ -113,16 +132,59 @@ class DomDiffBridge {
 
     // 捕获document.body
     const rootNode = this.captureDomNode(document.body);
-    const nodeCount = this.addDomNodeToWasm(treeId, rootNode);
+    this.addDomNodeToWasm(treeId, rootNode);
+    const truncation = this.truncateTree(treeId);
+    const nodeCount = truncation.retainedNodes;
 
     const duration = performance.now() - startTime;
 
     console.log(`[Bridge] DOM captured: ${nodeCount} nodes in ${duration.toFixed(2)}ms`);
+    if (truncation.truncated) {
+      console.warn('[Bridge] DOM truncated:', truncation);
+    }
 
     return {
       treeId: Number(treeId),
       nodeCount,
-      duration
+      duration,
+      truncation
+    };
+  }
+
+  /**
+   * 超过默认上限时截断DOM树，返回截断报告
+   */
+  private truncateTree(treeId: bigint): TruncationReport {
+    if (this.wasm.dom_tree_truncate(treeId, 0, 0) === 0) {
+      throw new Error('Failed to truncate tree');
+    }
+
+    const fields: number[] = [];
+    for (let i = 0; i < TRUNCATION_REPORT_LEN; i++) {
+      fields.push(this.wasm.dom_tree_truncation_field(treeId, i));
+    }
+
+    const [
+      originalNodes,
+      originalBytes,
+      retainedNodes,
+      retainedBytes,
+      truncatedTexts,
+      depthPrunedNodes,
+      subtreePrunedNodes,
+      limitPrunedNodes
+    ] = fields;
+
+    return {
+      originalNodes,
+      originalBytes,
+      retainedNodes,
+      retainedBytes,
+      truncatedTexts,
+      depthPrunedNodes,
+      subtreePrunedNodes,
+      limitPrunedNodes,
+      truncated: retainedNodes < originalNodes || truncatedTexts > 0
     };
   }
 
@@ -12,7 +12,12 @@ interface WasmModule {
   dom_tree_append_child: (treeId: bigint, parentId: bigint, childId: bigint) => bigint;
   dom_tree_node_count: (treeId: bigint) => bigint;
   dom_tree_delete: (treeId: bigint) => bigint;
+  dom_tree_truncate: (treeId: bigint, maxNodes: number, maxBytes: number) => number;
+  dom_tree_truncation_field: (treeId: bigint, index: number) => number;
+  dom_tree_has_node: (treeId: bigint, nodeId: bigint) => number;
   diff_compute: (tree1Id: bigint, tree2Id: bigint) => bigint;
+  diff_compute_with_algorithm: (tree1Id: bigint, tree2Id: bigint, algorithm: number, outChangesPtr: number, outAlgorithmPtr: number, outElapsedUsPtr: number) => number;
+  diff_compute_normalized: (tree1Id: bigint, tree2Id: bigint, flags: number, baseUrlPtr: number, baseUrlLen: number, algorithm: number, outChangesPtr: number, outAlgorithmPtr: number, outElapsedUsPtr: number) => number;
   diff_get_changes: (tree1Id: bigint, tree2Id: bigint, outChanges: bigint, outCapacity: bigint) => bigint;
   arena_create: () => bigint;
   monitoring_record_latency_us: (namePtr: bigint, nameLen: bigint, valueUs: number) => void;
@@ -57,7 +57,8 @@ async function handleCaptureDom() {
       result: {
         treeId: result.treeId,
         nodeCount: result.nodeCount,
-        duration: result.duration.toFixed(2)
+        duration: result.duration.toFixed(2),
+        truncation: result.truncation
       }
     };
   } catch (error) {
@@ -91,6 +91,11 @@ async function handleCapture() {
       log('   节点数: ' + response.result.nodeCount, 'info');
       log('   耗时: ' + response.result.duration + 'ms', 'info');
 
+      var truncation = response.result.truncation;
+      if (truncation && truncation.truncated) {
+        log('   ⚠️ 页面超过上限已截断: ' + truncation.originalNodes + ' → ' + truncation.retainedNodes + ' 个节点', 'warning');
+      }
+
       nodeCountEl.textContent = response.result.nodeCount;
       updateStatus('已捕获');
     } else {
@@ -0,0 +1,877 @@
+//! # 可插拔差分算法
+//!
+//! 单一算法无法同时兼顾 5MB 级别的大 DOM 和需要精确结果的小片段，
+//! 因此每次差分请求可以选择算法，并受各自的节点数上限约束。
+//!
+//! ## 可选算法
+//!
+//! - **Heuristic**：基于哈希的 O(n) 启发式算法（[`compute_tree_diff`]），适合大 DOM
+//! - **TreeEditDistance**：Zhang-Shasha 精确树编辑距离，O(n²) 以上复杂度，仅适合小片段
+//! - **TextOnly**：只比较文本节点序列，忽略结构和属性
+//! - **Auto**：精确算法的估算代价不超过上限时使用精确算法，否则退回启发式算法
+//!
+//! ## 精确算法代价
+//!
+//! Zhang-Shasha 的耗时取决于树形而非节点数：需要计算的森林距离单元数为
+//! `Σ|keyroots_a 子树| × Σ|keyroots_b 子树|`，深而宽的树（如嵌套 div 带文本兄弟）
+//! 在 1000 个节点时即可达到 10¹¹ 量级。因此按该单元数（[`DiffOptions::edit_cell_limit`]）
+//! 而不是节点数约束精确算法。
+//!
+//! 每次计算的耗时记录在 [`DiffReport`] 中，并以 `diff_<algorithm>_us` 写入全局性能监控。
+
+use crate::dom::{DomNode, DomTree, NodeId};
+use crate::diff::tree_diff::{DiffChange, TreeDiff, compare_nodes, compute_tree_diff};
+use crate::monitoring;
+use std::collections::HashMap;
+use std::fmt;
+use std::time::{Duration, Instant};
+
+/// 精确树编辑距离默认节点上限（两棵树合计）
+pub const TREE_EDIT_DISTANCE_MAX_NODES: usize = 2_000;
+
+/// 精确树编辑距离默认森林距离单元数上限（约 100ms）
+pub const TREE_EDIT_DISTANCE_MAX_CELLS: u64 = 4_000_000;
+
+/// 文本 LCS 对齐的最大表格单元数，超出后按位置对齐
+const TEXT_LCS_MAX_CELLS: usize = 4_000_000;
+
+/// 差分算法
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq, Hash)]
+pub enum DiffAlgorithm {
+    /// 按精确算法的森林距离单元数自动选择（见 [`DiffOptions::edit_cell_limit`]）
+    #[default]
+    Auto,
+    /// 基于哈希的启发式算法
+    Heuristic,
+    /// 精确树编辑距离
+    TreeEditDistance,
+    /// 仅比较文本
+    TextOnly,
+}
+
+impl DiffAlgorithm {
+    /// 算法名称（用于监控指标）
+    #[must_use]
+    pub const fn name(self) -> &'static str {
+        match self {
+            Self::Auto => "auto",
+            Self::Heuristic => "heuristic",
+            Self::TreeEditDistance => "tree_edit_distance",
+            Self::TextOnly => "text_only",
+        }
+    }
+
+    /// 默认节点数上限（两棵树合计，`None` 表示不限制）
+    #[must_use]
+    pub const fn default_node_limit(self) -> Option<usize> {
+        match self {
+            Self::TreeEditDistance => Some(TREE_EDIT_DISTANCE_MAX_NODES),
+            Self::Auto | Self::Heuristic | Self::TextOnly => None,
+        }
+    }
+}
+
+impl fmt::Display for DiffAlgorithm {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        f.write_str(self.name())
+    }
+}
+
+/// 差分请求配置
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub struct DiffOptions {
+    /// 使用的算法
+    pub algorithm: DiffAlgorithm,
+    /// 节点数上限（两棵树合计，`None` 表示不限制）
+    pub node_limit: Option<usize>,
+    /// 精确算法的森林距离单元数上限（`Auto` 据此选择算法）
+    pub edit_cell_limit: u64,
+}
+
+impl Default for DiffOptions {
+    fn default() -> Self {
+        Self::new(DiffAlgorithm::Auto)
+    }
+}
+
+impl DiffOptions {
+    /// 使用算法默认上限创建配置
+    #[must_use]
+    pub const fn new(algorithm: DiffAlgorithm) -> Self {
+        Self {
+            algorithm,
+            node_limit: algorithm.default_node_limit(),
+            edit_cell_limit: TREE_EDIT_DISTANCE_MAX_CELLS,
+        }
+    }
+
+    /// 覆盖节点数上限
+    #[must_use]
+    pub const fn with_node_limit(mut self, limit: usize) -> Self {
+        self.node_limit = Some(limit);
+        self
+    }
+
+    /// 覆盖精确算法的森林距离单元数上限
+    #[must_use]
+    pub const fn with_edit_cell_limit(mut self, limit: u64) -> Self {
+        self.edit_cell_limit = limit;
+        self
+    }
+}
+
+/// 差分错误
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+pub enum DiffError {
+    /// 节点数超过算法上限
+    TooManyNodes {
+        algorithm: DiffAlgorithm,
+        nodes: usize,
+        limit: usize,
+    },
+    /// 精确算法的森林距离单元数超过上限
+    TooExpensive {
+        cells: u64,
+        limit: u64,
+    },
+}
+
+impl fmt::Display for DiffError {
+    fn fmt(&self, f: &mut fmt::Formatter<'_>) -> fmt::Result {
+        match self {
+            Self::TooManyNodes { algorithm, nodes, limit } => {
+                write!(f, "{algorithm} diff limited to {limit} nodes, got {nodes}")
+            }
+            Self::TooExpensive { cells, limit } => {
+                write!(f, "tree_edit_distance diff limited to {limit} cells, got {cells}")
+            }
+        }
+    }
+}
+
+impl std::error::Error for DiffError {}
+
+/// 差分结果报告
+#[derive(Debug, Clone)]
+pub struct DiffReport {
+    /// 实际使用的算法（`Auto` 会被解析为具体算法）
+    pub algorithm: DiffAlgorithm,
+    /// 差异结果
+    pub diff: TreeDiff,
+    /// 参与比较的节点数（两棵树合计）
+    pub node_count: usize,
+    /// 计算耗时
+    pub elapsed: Duration,
+    /// 树编辑距离（仅精确算法）
+    pub edit_distance: Option<usize>,
+}
+
+/// 按配置选择算法计算两棵树的差异
+///
+/// # Example
+///
+/// ```rust
+/// use chrome_dom_diff::diff::algorithm::{compute_diff, DiffAlgorithm, DiffOptions};
+/// use chrome_dom_diff::dom::{DomNode, DomTree};
+///
+/// let mut tree = DomTree::new();
+/// tree.add_node(DomNode::new_element(1, "div"));
+/// tree.set_root(1);
+///
+/// let report = compute_diff(&tree, &tree, &DiffOptions::new(DiffAlgorithm::TreeEditDistance)).unwrap();
+/// assert_eq!(report.edit_distance, Some(0));
+/// ```
+pub fn compute_diff(old: &DomTree, new: &DomTree, options: &DiffOptions) -> Result<DiffReport, DiffError> {
+    let node_count = old.node_count() + new.node_count();
+
+    if let Some(limit) = options.node_limit {
+        if node_count > limit {
+            return Err(DiffError::TooManyNodes {
+                algorithm: options.algorithm,
+                nodes: node_count,
+                limit,
+            });
+        }
+    }
+
+    let start = Instant::now();
+    let mut exact = None;
+    let algorithm = match options.algorithm {
+        // 单元数下界为 n × m，超出时无需构建后序树
+        DiffAlgorithm::Auto
+            if (old.node_count() as u64).saturating_mul(new.node_count() as u64) > options.edit_cell_limit =>
+        {
+            DiffAlgorithm::Heuristic
+        }
+        DiffAlgorithm::Auto | DiffAlgorithm::TreeEditDistance => {
+            let (a, b) = (PostorderTree::new(old), PostorderTree::new(new));
+            let cells = a.subtree_cells().saturating_mul(b.subtree_cells());
+            if cells <= options.edit_cell_limit {
+                exact = Some((a, b));
+                DiffAlgorithm::TreeEditDistance
+            } else if options.algorithm == DiffAlgorithm::Auto {
+                DiffAlgorithm::Heuristic
+            } else {
+                return Err(DiffError::TooExpensive { cells, limit: options.edit_cell_limit });
+            }
+        }
+        other => other,
+    };
+
+    let (diff, edit_distance) = match (algorithm, exact) {
+        (_, Some((a, b))) => {
+            let (diff, distance) = compute_tree_edit_diff(old, new, &a, &b);
+            (diff, Some(distance))
+        }
+        (DiffAlgorithm::TextOnly, None) => (compute_text_diff(old, new), None),
+        _ => (compute_tree_diff(old, new), None),
+    };
+    let elapsed = start.elapsed();
+
+    monitoring::record_latency_us(&format!("diff_{}_us", algorithm.name()), elapsed.as_secs_f64() * 1_000_000.0);
+
+    Ok(DiffReport {
+        algorithm,
+        diff,
+        node_count,
+        elapsed,
+        edit_distance,
+    })
+}
+
+// ============================================
+// 精确树编辑距离（Zhang-Shasha）
+// ============================================
+
+/// 后序编号的树（编号从 1 开始）
+struct PostorderTree<'a> {
+    nodes: Vec<&'a DomNode>,
+    /// 每个节点最左叶子的后序编号（下标 0 为占位）
+    leftmost: Vec<usize>,
+    /// 关键根（升序）
+    keyroots: Vec<usize>,
+    /// 节点 ID 到后序编号
+    index: HashMap<NodeId, usize>,
+}
+
+impl<'a> PostorderTree<'a> {
+    fn new(tree: &'a DomTree) -> Self {
+        let mut nodes: Vec<&DomNode> = Vec::with_capacity(tree.node_count() + 1);
+        let mut leftmost = vec![0];
+        let mut subtree_leftmost: Vec<usize> = Vec::with_capacity(tree.node_count());
+
+        // 显式栈后序遍历：(节点, 子节点是否已入栈)；subtree_leftmost 暂存已完成子树的最左叶子
+        let mut stack: Vec<(NodeId, bool)> = tree.root().map(|root| (root, false)).into_iter().collect();
+        while let Some((id, expanded)) = stack.pop() {
+            let Some(node) = tree.get_node(id) else {
+                continue;
+            };
+
+            if expanded {
+                nodes.push(node);
+                let index = nodes.len();
+                let present_children = node.children.iter().filter(|&&child| tree.get_node(child).is_some()).count();
+                // 叶子节点的最左叶子是自身，否则继承第一个子节点的最左叶子
+                let left = if present_children == 0 {
+                    index
+                } else {
+                    let start = subtree_leftmost.len() - present_children;
+                    let left = subtree_leftmost[start];
+                    subtree_leftmost.truncate(start);
+                    left
+                };
+                leftmost.push(left);
+                subtree_leftmost.push(left);
+            } else {
+                stack.push((id, true));
+                for &child in node.children.iter().rev() {
+                    stack.push((child, false));
+                }
+            }
+        }
+
+        let len = nodes.len();
+        let mut seen = vec![false; len + 1];
+        let mut keyroots = Vec::new();
+        for i in (1..=len).rev() {
+            if !seen[leftmost[i]] {
+                seen[leftmost[i]] = true;
+                keyroots.push(i);
+            }
+        }
+        keyroots.reverse();
+
+        let index = nodes.iter().enumerate().map(|(i, node)| (node.id, i + 1)).collect();
+
+        Self {
+            nodes,
+            leftmost,
+            keyroots,
+            index,
+        }
+    }
+
+    fn len(&self) -> usize {
+        self.nodes.len()
+    }
+
+    /// 所有关键根子树大小之和（与另一棵树的该值相乘即为森林距离单元数）
+    fn subtree_cells(&self) -> u64 {
+        self.keyroots.iter().map(|&k| (k - self.leftmost[k] + 1) as u64).sum()
+    }
+
+    /// 父节点的后序编号
+    fn parent(&self, index: usize) -> Option<usize> {
+        self.node(index).parent.and_then(|parent| self.index.get(&parent).copied())
+    }
+
+    /// 按后序编号获取节点
+    fn node(&self, index: usize) -> &'a DomNode {
+        self.nodes[index - 1]
+    }
+}
+
+/// 重标记代价：完全相同为 0，属性/文本不同为 1，类型或标签不同则不允许重标记（等价于删除+插入）
+fn relabel_cost(old: &DomNode, new: &DomNode) -> usize {
+    if old.node_type != new.node_type || old.tag_name != new.tag_name {
+        return 2;
+    }
+
+    let same_attrs = old.attributes.len() == new.attributes.len()
+        && old.attributes.iter().all(|(name, value)| new.get_attr(name) == Some(value.as_str()));
+
+    if same_attrs && old.text_content == new.text_content {
+        0
+    } else {
+        1
+    }
+}
+
+/// 计算子树对 (i, j) 的森林距离表，并写入完成的子树距离
+fn forest_distance(a: &PostorderTree<'_>, b: &PostorderTree<'_>, i: usize, j: usize, treedist: &mut [Vec<usize>]) -> Vec<Vec<usize>> {
+    let (li, lj) = (a.leftmost[i], b.leftmost[j]);
+    let (rows, cols) = (i - li + 2, j - lj + 2);
+    let mut fd = vec![vec![0; cols]; rows];
+
+    for x in 1..rows {
+        fd[x][0] = fd[x - 1][0] + 1;
+    }
+    for y in 1..cols {
+        fd[0][y] = fd[0][y - 1] + 1;
+    }
+
+    for x in 1..rows {
+        let node_x = x + li - 1;
+        for y in 1..cols {
+            let node_y = y + lj - 1;
+            let delete = fd[x - 1][y] + 1;
+            let insert = fd[x][y - 1] + 1;
+
+            if a.leftmost[node_x] == li && b.leftmost[node_y] == lj {
+                let relabel = fd[x - 1][y - 1] + relabel_cost(a.node(node_x), b.node(node_y));
+                fd[x][y] = delete.min(insert).min(relabel);
+                treedist[node_x][node_y] = fd[x][y];
+            } else {
+                let subtree = fd[a.leftmost[node_x] - li][b.leftmost[node_y] - lj] + treedist[node_x][node_y];
+                fd[x][y] = delete.min(insert).min(subtree);
+            }
+        }
+    }
+
+    fd
+}
+
+/// 使用精确树编辑距离计算差异
+///
+/// 删除节点时其子节点会提升到父节点下（插入同理），因此除最上层的删除/插入外，
+/// 父节点未相互映射的保留节点还会生成 `Move`：
+/// `div>section>p` → `div>p` 生成 `Delete(section)` 和 `Move(p: section → div)`。
+///
+/// 各字段引用的树：
+///
+/// - `Update.node`、`Insert.parent/node`、`Move.to_parent/node`：新树节点 ID
+/// - `Delete.parent/node`、`Move.from_parent`：旧树节点 ID
+fn compute_tree_edit_diff(old: &DomTree, new: &DomTree, a: &PostorderTree<'_>, b: &PostorderTree<'_>) -> (TreeDiff, usize) {
+    let (n, m) = (a.len(), b.len());
+
+    let mut diff = TreeDiff::new();
+    if n == 0 || m == 0 {
+        if let Some(root) = old.root() {
+            diff.add_change(delete_change(old, root));
+        }
+        if let Some(root) = new.root() {
+            diff.add_change(insert_change(new, root));
+        }
+        return (diff, n + m);
+    }
+
+    let mut treedist = vec![vec![0; m + 1]; n + 1];
+    for &i in &a.keyroots {
+        for &j in &b.keyroots {
+            forest_distance(a, b, i, j, &mut treedist);
+        }
+    }
+    let distance = treedist[n][m];
+
+    // 回溯映射（显式栈代替递归）
+    let mut deleted = vec![false; n + 1];
+    let mut inserted = vec![false; m + 1];
+    let mut mapping = Vec::new();
+    let mut pending = vec![(n, m)];
+
+    while let Some((i, j)) = pending.pop() {
+        let fd = forest_distance(a, b, i, j, &mut treedist);
+        let (li, lj) = (a.leftmost[i], b.leftmost[j]);
+        let (mut x, mut y) = (i - li + 1, j - lj + 1);
+
+        while x > 0 || y > 0 {
+            if x > 0 && fd[x][y] == fd[x - 1][y] + 1 {
+                deleted[x + li - 1] = true;
+                x -= 1;
+            } else if y > 0 && fd[x][y] == fd[x][y - 1] + 1 {
+                inserted[y + lj - 1] = true;
+                y -= 1;
+            } else {
+                let (node_x, node_y) = (x + li - 1, y + lj - 1);
+                if a.leftmost[node_x] == li && b.leftmost[node_y] == lj {
+                    mapping.push((node_x, node_y));
+                    x -= 1;
+                    y -= 1;
+                } else {
+                    pending.push((node_x, node_y));
+                    x = a.leftmost[node_x] - li;
+                    y = b.leftmost[node_y] - lj;
+                }
+            }
+        }
+    }
+
+    mapping.sort_unstable();
+    let mut mapped = vec![None; n + 1];
+    for &(x, y) in &mapping {
+        mapped[x] = Some(y);
+        compare_nodes(a.node(x), b.node(y), &mut diff);
+    }
+
+    // 只记录最上层的插入节点
+    for y in (1..=m).filter(|&y| inserted[y]) {
+        if !b.parent(y).is_some_and(|parent| inserted[parent]) {
+            diff.add_change(insert_change(new, b.node(y).id));
+        }
+    }
+
+    // 父节点未相互映射的保留节点（祖先被删除/插入，或跨父节点移动）
+    for &(x, y) in &mapping {
+        let (old_parent, new_parent) = (a.parent(x), b.parent(y));
+        let same_parent = match (old_parent, new_parent) {
+            (Some(old_parent), Some(new_parent)) => mapped[old_parent] == Some(new_parent),
+            (None, None) => true,
+            _ => false,
+        };
+        if same_parent {
+            continue;
+        }
+        let from_parent = old_parent.map_or(a.node(x).id, |parent| a.node(parent).id);
+        let (to_parent, index) = position_in_parent(new, b.node(y).id);
+        diff.add_change(DiffChange::Move {
+            from_parent,
+            to_parent,
+            index,
+            node: b.node(y).id,
+        });
+    }
+
+    // 只记录最上层的删除节点，未映射的后代随之删除
+    for x in (1..=n).filter(|&x| deleted[x]) {
+        if !a.parent(x).is_some_and(|parent| deleted[parent]) {
+            diff.add_change(delete_change(old, a.node(x).id));
+        }
+    }
+
+    (diff, distance)
+}
+
+// ============================================
+// 仅文本差分
+// ============================================
+
+/// 仅比较文本节点序列（前序），结构与属性变化被忽略
+fn compute_text_diff(old: &DomTree, new: &DomTree) -> TreeDiff {
+    let old_texts = collect_text_nodes(old);
+    let new_texts = collect_text_nodes(new);
+    let mut diff = TreeDiff::new();
+
+    // 跳过公共前缀和后缀
+    let prefix = old_texts
+        .iter()
+        .zip(&new_texts)
+        .take_while(|(a, b)| a.text_content == b.text_content)
+        .count();
+    let suffix = old_texts[prefix..]
+        .iter()
+        .rev()
+        .zip(new_texts[prefix..].iter().rev())
+        .take_while(|(a, b)| a.text_content == b.text_content)
+        .count();
+
+    let old_mid = &old_texts[prefix..old_texts.len() - suffix];
+    let new_mid = &new_texts[prefix..new_texts.len() - suffix];
+
+    if old_mid.len().saturating_mul(new_mid.len()) > TEXT_LCS_MAX_CELLS {
+        // 表格过大，按位置对齐
+        for (a, b) in old_mid.iter().zip(new_mid) {
+            compare_nodes(a, b, &mut diff);
+        }
+        for a in old_mid.iter().skip(new_mid.len()) {
+            diff.add_change(delete_change(old, a.id));
+        }
+        for b in new_mid.iter().skip(old_mid.len()) {
+            diff.add_change(insert_change(new, b.id));
+        }
+        return diff;
+    }
+
+    // LCS 对齐
+    let (n, m) = (old_mid.len(), new_mid.len());
+    let mut lcs = vec![vec![0_u32; m + 1]; n + 1];
+    for x in (0..n).rev() {
+        for y in (0..m).rev() {
+            lcs[x][y] = if old_mid[x].text_content == new_mid[y].text_content {
+                lcs[x + 1][y + 1] + 1
+            } else {
+                lcs[x + 1][y].max(lcs[x][y + 1])
+            };
+        }
+    }
+
+    let (mut x, mut y) = (0, 0);
+    while x < n || y < m {
+        if x < n && y < m && old_mid[x].text_content == new_mid[y].text_content {
+            x += 1;
+            y += 1;
+        } else if y == m || (x < n && lcs[x + 1][y] >= lcs[x][y + 1]) {
+            diff.add_change(delete_change(old, old_mid[x].id));
+            x += 1;
+        } else {
+            diff.add_change(insert_change(new, new_mid[y].id));
+            y += 1;
+        }
+    }
+
+    diff
+}
+
+/// 按前序收集文本节点
+fn collect_text_nodes(tree: &DomTree) -> Vec<&DomNode> {
+    tree.iter()
+        .filter_map(|id| tree.get_node(id))
+        .filter(|node| node.is_text())
+        .collect()
+}
+
+// ============================================
+// 辅助函数
+// ============================================
+
+/// 节点在父节点中的位置（无父节点时以自身作为父节点，与启发式算法一致）
+fn position_in_parent(tree: &DomTree, id: NodeId) -> (NodeId, usize) {
+    let parent = tree.get_node(id).and_then(|node| node.parent);
+    let index = parent
+        .and_then(|parent| tree.get_node(parent))
+        .and_then(|parent| parent.children.iter().position(|&child| child == id))
+        .unwrap_or(0);
+
+    (parent.unwrap_or(id), index)
+}
+
+fn delete_change(tree: &DomTree, node: NodeId) -> DiffChange {
+    let (parent, index) = position_in_parent(tree, node);
+    DiffChange::Delete { parent, index, node }
+}
+
+fn insert_change(tree: &DomTree, node: NodeId) -> DiffChange {
+    let (parent, index) = position_in_parent(tree, node);
+    DiffChange::Insert { parent, index, node }
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::dom::DomNode;
+
+    /// 构建树：div > [span > "hello", p > "world"]
+    fn create_tree() -> DomTree {
+        let mut tree = DomTree::new();
+
+        tree.add_node(DomNode::new_element(1, "div"));
+        tree.add_node(DomNode::new_element(2, "span"));
+        tree.add_node(DomNode::new_text(3, "hello"));
+        tree.add_node(DomNode::new_element(4, "p"));
+        tree.add_node(DomNode::new_text(5, "world"));
+        tree.set_root(1);
+
+        tree.append_child(1, 2);
+        tree.append_child(2, 3);
+        tree.append_child(1, 4);
+        tree.append_child(4, 5);
+
+        tree
+    }
+
+    /// 构建深而宽的树：每层 div 下一个文本节点和下一层 div（共 `nodes` 个节点）
+    fn create_deep_tree(nodes: NodeId) -> DomTree {
+        let mut tree = DomTree::new();
+        tree.add_node(DomNode::new_element(1, "div"));
+        tree.set_root(1);
+
+        let mut parent = 1;
+        for id in (2..nodes).step_by(2) {
+            tree.add_node(DomNode::new_text(id, "text"));
+            tree.add_node(DomNode::new_element(id + 1, "div"));
+            tree.append_child(parent, id);
+            tree.append_child(parent, id + 1);
+            parent = id + 1;
+        }
+
+        tree
+    }
+
+    #[test]
+    fn test_auto_selects_by_size() {
+        let tree = create_tree();
+
+        let report = compute_diff(&tree, &tree, &DiffOptions::default()).unwrap();
+        assert_eq!(report.algorithm, DiffAlgorithm::TreeEditDistance);
+        assert_eq!(report.node_count, 10);
+
+        let mut big = DomTree::new();
+        big.add_node(DomNode::new_element(1, "ul"));
+        big.set_root(1);
+        for id in 2..=(TREE_EDIT_DISTANCE_MAX_NODES as NodeId) {
+            big.add_node(DomNode::new_element(id, "li"));
+            big.append_child(1, id);
+        }
+
+        let report = compute_diff(&big, &big, &DiffOptions::default()).unwrap();
+        assert_eq!(report.algorithm, DiffAlgorithm::Heuristic);
+    }
+
+    #[test]
+    fn test_auto_bounds_exact_cost() {
+        // 999 个节点，森林距离单元数约 6×10¹⁰，精确算法需要数分钟
+        let old = create_deep_tree(999);
+        let mut new = create_deep_tree(999);
+        new.get_node_mut(500).unwrap().text_content = Some("changed".to_string());
+
+        let start = Instant::now();
+        let report = compute_diff(&old, &new, &DiffOptions::default()).unwrap();
+
+        assert_eq!(report.algorithm, DiffAlgorithm::Heuristic);
+        assert!(start.elapsed() < Duration::from_secs(2));
+
+        // 小的深树仍使用精确算法
+        let small = create_deep_tree(21);
+        let report = compute_diff(&small, &small, &DiffOptions::default()).unwrap();
+        assert_eq!(report.algorithm, DiffAlgorithm::TreeEditDistance);
+    }
+
+    #[test]
+    fn test_tree_edit_distance_too_expensive() {
+        let tree = create_deep_tree(999);
+        let options = DiffOptions::new(DiffAlgorithm::TreeEditDistance);
+
+        let err = compute_diff(&tree, &tree, &options).unwrap_err();
+
+        assert!(matches!(err, DiffError::TooExpensive { limit: TREE_EDIT_DISTANCE_MAX_CELLS, .. }));
+
+        let options = options.with_edit_cell_limit(0);
+        let err = compute_diff(&create_tree(), &create_tree(), &options).unwrap_err();
+        assert_eq!(err, DiffError::TooExpensive { cells: 49, limit: 0 });
+        assert_eq!(err.to_string(), "tree_edit_distance diff limited to 0 cells, got 49");
+    }
+
+    #[test]
+    fn test_node_limit() {
+        let tree = create_tree();
+
+        let options = DiffOptions::new(DiffAlgorithm::Heuristic).with_node_limit(5);
+        let err = compute_diff(&tree, &tree, &options).unwrap_err();
+
+        assert_eq!(
+            err,
+            DiffError::TooManyNodes {
+                algorithm: DiffAlgorithm::Heuristic,
+                nodes: 10,
+                limit: 5,
+            }
+        );
+        assert_eq!(err.to_string(), "heuristic diff limited to 5 nodes, got 10");
+    }
+
+    #[test]
+    fn test_tree_edit_distance_identical() {
+        let tree = create_tree();
+        let options = DiffOptions::new(DiffAlgorithm::TreeEditDistance);
+
+        let report = compute_diff(&tree, &tree, &options).unwrap();
+
+        assert_eq!(report.edit_distance, Some(0));
+        assert!(!report.diff.has_changes());
+    }
+
+    #[test]
+    fn test_tree_edit_distance_update() {
+        let old = create_tree();
+        let mut new = create_tree();
+        new.get_node_mut(5).unwrap().text_content = Some("rust".to_string());
+        new.get_node_mut(2).unwrap().attributes.push(("class".to_string(), "title".to_string()));
+
+        let report = compute_diff(&old, &new, &DiffOptions::new(DiffAlgorithm::TreeEditDistance)).unwrap();
+
+        assert_eq!(report.edit_distance, Some(2));
+        assert_eq!(report.diff.change_count(), 2);
+        assert!(report.diff.changes.iter().all(|c| matches!(c, DiffChange::Update { .. })));
+    }
+
+    #[test]
+    fn test_tree_edit_distance_insert_delete() {
+        let old = create_tree();
+        let mut new = create_tree();
+
+        // 删除 span 子树，新增 em 子树
+        new.remove_subtree(2);
+        new.add_node(DomNode::new_element(6, "em"));
+        new.add_node(DomNode::new_text(7, "new"));
+        new.append_child(1, 6);
+        new.append_child(6, 7);
+
+        let report = compute_diff(&old, &new, &DiffOptions::new(DiffAlgorithm::TreeEditDistance)).unwrap();
+
+        assert_eq!(report.edit_distance, Some(4));
+        assert_eq!(report.diff.change_count(), 2);
+        assert_eq!(report.diff.deletes.get(&2), Some(&(1, 0)));
+        assert_eq!(report.diff.inserts.get(&6), Some(&(1, 1)));
+    }
+
+    /// 构建树：div > section > p > "x"
+    fn create_wrapped_tree() -> DomTree {
+        let mut tree = DomTree::new();
+
+        tree.add_node(DomNode::new_element(1, "div"));
+        tree.add_node(DomNode::new_element(2, "section"));
+        tree.add_node(DomNode::new_element(3, "p"));
+        tree.add_node(DomNode::new_text(4, "x"));
+        tree.set_root(1);
+
+        tree.append_child(1, 2);
+        tree.append_child(2, 3);
+        tree.append_child(3, 4);
+
+        tree
+    }
+
+    /// 构建树：div > p > "x"（ID 从 11 开始，与 [`create_wrapped_tree`] 不重叠）
+    fn create_unwrapped_tree() -> DomTree {
+        let mut tree = DomTree::new();
+
+        tree.add_node(DomNode::new_element(11, "div"));
+        tree.add_node(DomNode::new_element(12, "p"));
+        tree.add_node(DomNode::new_text(13, "x"));
+        tree.set_root(11);
+
+        tree.append_child(11, 12);
+        tree.append_child(12, 13);
+
+        tree
+    }
+
+    #[test]
+    fn test_tree_edit_distance_unwrap() {
+        let old = create_wrapped_tree();
+        let new = create_unwrapped_tree();
+
+        let report = compute_diff(&old, &new, &DiffOptions::new(DiffAlgorithm::TreeEditDistance)).unwrap();
+
+        // 删除旧树的 section(2)，新树的 p(12) 从 section 提升到 div(11) 下
+        assert_eq!(report.edit_distance, Some(1));
+        assert_eq!(
+            report.diff.changes,
+            vec![
+                DiffChange::Move {
+                    from_parent: 2,
+                    to_parent: 11,
+                    index: 0,
+                    node: 12,
+                },
+                DiffChange::Delete {
+                    parent: 1,
+                    index: 0,
+                    node: 2,
+                },
+            ]
+        );
+    }
+
+    #[test]
+    fn test_tree_edit_distance_wrap() {
+        let old = create_unwrapped_tree();
+        let new = create_wrapped_tree();
+
+        let report = compute_diff(&old, &new, &DiffOptions::new(DiffAlgorithm::TreeEditDistance)).unwrap();
+
+        // 插入新树的 section(2)，新树的 p(3) 从 div(11) 移入 section
+        assert_eq!(report.edit_distance, Some(1));
+        assert_eq!(
+            report.diff.changes,
+            vec![
+                DiffChange::Insert {
+                    parent: 1,
+                    index: 0,
+                    node: 2,
+                },
+                DiffChange::Move {
+                    from_parent: 11,
+                    to_parent: 2,
+                    index: 0,
+                    node: 3,
+                },
+            ]
+        );
+    }
+
+    #[test]
+    fn test_tree_edit_distance_empty_tree() {
+        let old = DomTree::new();
+        let new = create_tree();
+
+        let report = compute_diff(&old, &new, &DiffOptions::new(DiffAlgorithm::TreeEditDistance)).unwrap();
+
+        assert_eq!(report.edit_distance, Some(5));
+        assert_eq!(report.diff.inserts.get(&1), Some(&(1, 0)));
+    }
+
+    #[test]
+    fn test_text_only_ignores_structure() {
+        let old = create_tree();
+        let mut new = create_tree();
+        new.get_node_mut(4).unwrap().tag_name = Some("section".to_string());
+        new.get_node_mut(2).unwrap().attributes.push(("class".to_string(), "title".to_string()));
+
+        let report = compute_diff(&old, &new, &DiffOptions::new(DiffAlgorithm::TextOnly)).unwrap();
+
+        assert!(!report.diff.has_changes());
+    }
+
+    #[test]
+    fn test_text_only_changes() {
+        let old = create_tree();
+        let mut new = create_tree();
+        new.get_node_mut(3).unwrap().text_content = Some("goodbye".to_string());
+
+        let report = compute_diff(&old, &new, &DiffOptions::new(DiffAlgorithm::TextOnly)).unwrap();
+
+        assert_eq!(report.diff.change_count(), 2);
+        assert!(report.diff.deletes.contains_key(&3));
+        assert!(report.diff.inserts.contains_key(&3));
+    }
+}
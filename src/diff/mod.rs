@@ -7,12 +7,18 @@
 //! - [`ops_generator`] - 操作序列生成器
 //! - [`tree_diff`] - 树差异计算
 //! - [`hash`] - 快速节点哈希
+//! - [`normalize`] - 差分前的 HTML 规范化
+//! - [`algorithm`] - 可插拔差分算法选择
 
 pub mod ops_generator;
 pub mod tree_diff;
 pub mod hash;
+pub mod normalize;
+pub mod algorithm;
 
 // 导出核心类型
 pub use ops_generator::{DomOp, OpsGenerator, MutationRecord, MutationType, BatchOp};
 pub use tree_diff::{DiffChange, TreeDiff, compute_tree_diff};
 pub use hash::{hash_node, NodeHash};
+pub use normalize::{NormalizeOptions, NormalizeStats, normalize_tree, compute_normalized_diff};
+pub use algorithm::{DiffAlgorithm, DiffOptions, DiffReport, DiffError, compute_diff};
@@ -0,0 +1,450 @@
+//! # HTML 规范化
+//!
+//! 差分前的可配置规范化阶段，消除不同 Chrome 版本序列化差异带来的误报。
+//!
+//! ## 规范化步骤
+//!
+//! 1. **移除脚本/样式**：删除 `<script>` / `<style>` 整棵子树
+//! 2. **标签小写**：统一标签名大小写
+//! 3. **空白折叠**：连续空白折叠为单个空格，删除纯空白文本节点（`<pre>` / `<textarea>` 内保留）
+//! 4. **属性排序**：按属性名排序，消除属性顺序差异
+//! 5. **相对 URL 解析**：基于页面 URL 将 `href` / `src` 等属性解析为绝对地址
+
+use crate::dom::{DomTree, NodeId};
+use crate::diff::algorithm::{DiffError, DiffOptions, DiffReport, compute_diff};
+
+/// 需要整体移除的元素
+const STRIPPED_TAGS: &[&str] = &["script", "style"];
+
+/// 保留原始空白的元素
+const PRESERVE_WHITESPACE_TAGS: &[&str] = &["pre", "textarea"];
+
+/// 包含 URL 的属性
+const URL_ATTRIBUTES: &[&str] = &["href", "src", "action", "formaction", "poster", "cite"];
+
+/// 规范化配置
+#[derive(Debug, Clone, PartialEq, Eq)]
+pub struct NormalizeOptions {
+    /// 移除 `<script>` / `<style>` 子树
+    pub strip_scripts: bool,
+    /// 折叠空白并删除纯空白文本节点
+    pub collapse_whitespace: bool,
+    /// 按名称排序属性
+    pub sort_attributes: bool,
+    /// 标签名转为小写
+    pub lowercase_tags: bool,
+    /// 解析相对 URL 使用的页面地址（`None` 表示不解析）
+    pub base_url: Option<String>,
+}
+
+impl Default for NormalizeOptions {
+    fn default() -> Self {
+        Self {
+            strip_scripts: true,
+            collapse_whitespace: true,
+            sort_attributes: true,
+            lowercase_tags: true,
+            base_url: None,
+        }
+    }
+}
+
+impl NormalizeOptions {
+    /// 设置解析相对 URL 使用的页面地址
+    #[must_use]
+    pub fn with_base_url(mut self, base_url: impl Into<String>) -> Self {
+        self.base_url = Some(base_url.into());
+        self
+    }
+}
+
+/// 规范化统计
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+pub struct NormalizeStats {
+    /// 移除的节点数量
+    pub removed_nodes: usize,
+    /// 折叠过空白的文本节点数量
+    pub collapsed_texts: usize,
+    /// 解析为绝对地址的 URL 数量
+    pub resolved_urls: usize,
+}
+
+/// 对 DOM 树执行规范化（原地修改）
+///
+/// # Complexity
+///
+/// - 时间：O(n)，n 为节点数
+/// - 空间：O(n)
+pub fn normalize_tree(tree: &mut DomTree, options: &NormalizeOptions) -> NormalizeStats {
+    let mut stats = NormalizeStats::default();
+
+    if options.strip_scripts {
+        let stripped: Vec<NodeId> = tree
+            .iter()
+            .filter(|&id| tree.get_node(id).is_some_and(|node| has_tag(node.tag_name.as_deref(), STRIPPED_TAGS)))
+            .collect();
+
+        for id in stripped {
+            // 祖先已被移除时返回 0
+            stats.removed_nodes += tree.remove_subtree(id);
+        }
+    }
+
+    let ids: Vec<NodeId> = tree.iter().collect();
+
+    if options.collapse_whitespace {
+        for id in whitespace_candidates(tree) {
+            let Some(text) = tree.get_node(id).and_then(|node| node.text_content.as_deref()) else {
+                continue;
+            };
+
+            let collapsed = collapse_whitespace(text);
+            if collapsed.is_empty() {
+                stats.removed_nodes += tree.remove_subtree(id);
+            } else if collapsed != text {
+                if let Some(node) = tree.get_node_mut(id) {
+                    node.text_content = Some(collapsed);
+                }
+                stats.collapsed_texts += 1;
+            }
+        }
+    }
+
+    for &id in &ids {
+        let Some(node) = tree.get_node_mut(id) else {
+            continue;
+        };
+        if !node.is_element() {
+            continue;
+        }
+
+        if options.lowercase_tags {
+            if let Some(tag_name) = node.tag_name.as_mut() {
+                tag_name.make_ascii_lowercase();
+            }
+        }
+
+        if options.sort_attributes {
+            node.attributes.sort_by(|(a, _), (b, _)| a.cmp(b));
+        }
+
+        if let Some(base_url) = options.base_url.as_deref() {
+            for (name, value) in &mut node.attributes {
+                if !URL_ATTRIBUTES.iter().any(|attr| name.eq_ignore_ascii_case(attr)) {
+                    continue;
+                }
+                if let Some(resolved) = resolve_url(base_url, value) {
+                    if resolved != *value {
+                        *value = resolved;
+                        stats.resolved_urls += 1;
+                    }
+                }
+            }
+        }
+    }
+
+    stats
+}
+
+/// 规范化后按 `diff_options` 选择的算法计算两棵树的差异（不修改输入树）
+pub fn compute_normalized_diff(
+    old: &DomTree,
+    new: &DomTree,
+    options: &NormalizeOptions,
+    diff_options: &DiffOptions,
+) -> Result<DiffReport, DiffError> {
+    let mut old = old.clone();
+    let mut new = new.clone();
+
+    normalize_tree(&mut old, options);
+    normalize_tree(&mut new, options);
+
+    compute_diff(&old, &new, diff_options)
+}
+
+/// 将相对 URL 解析为绝对地址
+///
+/// 返回 `None` 表示保持原值：引用为空或已是绝对地址（含 `mailto:`、`data:` 等），
+/// 或 `base` 不是层级式绝对地址。
+///
+/// # Example
+///
+/// ```rust
+/// use chrome_dom_diff::diff::normalize::resolve_url;
+///
+/// let url = resolve_url("https://example.com/a/b.html", "../c.png");
+/// assert_eq!(url.as_deref(), Some("https://example.com/c.png"));
+/// ```
+#[must_use]
+pub fn resolve_url(base: &str, reference: &str) -> Option<String> {
+    let reference = reference.trim();
+    if reference.is_empty() || split_scheme(reference).is_some() {
+        return None;
+    }
+
+    let (scheme, rest) = split_scheme(base)?;
+    let rest = rest.strip_prefix("//")?;
+
+    let authority_end = rest.find(['/', '?', '#']).unwrap_or(rest.len());
+    let authority = &rest[..authority_end];
+    let base_path = rest[authority_end..].split(['?', '#']).next().unwrap_or("");
+
+    if let Some(network_path) = reference.strip_prefix("//") {
+        return Some(format!("{scheme}://{network_path}"));
+    }
+    if reference.starts_with('#') {
+        let without_fragment = base.split('#').next().unwrap_or(base);
+        return Some(format!("{without_fragment}{reference}"));
+    }
+    if reference.starts_with('?') {
+        return Some(format!("{scheme}://{authority}{base_path}{reference}"));
+    }
+
+    let suffix_start = reference.find(['?', '#']).unwrap_or(reference.len());
+    let (ref_path, ref_suffix) = reference.split_at(suffix_start);
+
+    let merged = if ref_path.starts_with('/') {
+        ref_path.to_string()
+    } else {
+        let base_dir = base_path.rfind('/').map_or("/", |pos| &base_path[..=pos]);
+        format!("{base_dir}{ref_path}")
+    };
+
+    Some(format!("{scheme}://{authority}{}{ref_suffix}", remove_dot_segments(&merged)))
+}
+
+/// 拆分 URL 协议（`scheme:rest`）
+fn split_scheme(url: &str) -> Option<(&str, &str)> {
+    let pos = url.find(':')?;
+    let scheme = &url[..pos];
+
+    let mut chars = scheme.chars();
+    let valid = chars.next().is_some_and(|c| c.is_ascii_alphabetic())
+        && chars.all(|c| c.is_ascii_alphanumeric() || matches!(c, '+' | '-' | '.'));
+
+    valid.then(|| (scheme, &url[pos + 1..]))
+}
+
+/// 移除路径中的 `.` / `..` 段（RFC 3986 5.2.4）
+fn remove_dot_segments(path: &str) -> String {
+    let segments: Vec<&str> = path.split('/').collect();
+    let mut output: Vec<&str> = Vec::with_capacity(segments.len());
+
+    for (i, &segment) in segments.iter().enumerate() {
+        let is_last = i + 1 == segments.len();
+        match segment {
+            "." => {}
+            ".." => {
+                // 保留开头的空段（绝对路径的根）
+                if output.len() > 1 {
+                    output.pop();
+                }
+            }
+            other => {
+                output.push(other);
+                continue;
+            }
+        }
+        if is_last {
+            output.push("");
+        }
+    }
+
+    output.join("/")
+}
+
+/// 折叠连续空白为单个空格并去除首尾空白
+fn collapse_whitespace(text: &str) -> String {
+    text.split_whitespace().collect::<Vec<_>>().join(" ")
+}
+
+/// 标签名是否属于给定集合（忽略大小写）
+fn has_tag(tag_name: Option<&str>, tags: &[&str]) -> bool {
+    tag_name.is_some_and(|name| tags.iter().any(|tag| name.eq_ignore_ascii_case(tag)))
+}
+
+/// 收集不在保留空白元素内的文本节点
+///
+/// 前序遍历时向下传递“位于保留空白元素内”标记，而不是为每个节点回溯祖先。
+fn whitespace_candidates(tree: &DomTree) -> Vec<NodeId> {
+    let mut candidates = Vec::new();
+    let mut stack: Vec<(NodeId, bool)> = tree.root().map(|root| (root, false)).into_iter().collect();
+
+    while let Some((id, preserved)) = stack.pop() {
+        let Some(node) = tree.get_node(id) else {
+            continue;
+        };
+        if preserved {
+            continue;
+        }
+        if node.is_text() {
+            candidates.push(id);
+        }
+
+        let preserved = has_tag(node.tag_name.as_deref(), PRESERVE_WHITESPACE_TAGS);
+        stack.extend(node.children.iter().rev().map(|&child| (child, preserved)));
+    }
+
+    candidates
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+    use crate::diff::algorithm::DiffAlgorithm;
+    use crate::diff::tree_diff::compute_tree_diff;
+    use crate::dom::DomNode;
+
+    /// 构建树：
+    ///
+    /// ```text
+    /// DIV
+    /// ├── SCRIPT
+    /// │   └── "var a = 1;"
+    /// ├── "  hello \n  world  "
+    /// ├── "   "
+    /// └── PRE
+    ///     └── "  keep  "
+    /// ```
+    fn create_tree() -> DomTree {
+        let mut tree = DomTree::new();
+
+        tree.add_node(DomNode::new_element(1, "DIV").with_attr("id", "main").with_attr("class", "box"));
+        tree.add_node(DomNode::new_element(2, "SCRIPT"));
+        tree.add_node(DomNode::new_text(3, "var a = 1;"));
+        tree.add_node(DomNode::new_text(4, "  hello \n  world  "));
+        tree.add_node(DomNode::new_text(5, "   "));
+        tree.add_node(DomNode::new_element(6, "PRE"));
+        tree.add_node(DomNode::new_text(7, "  keep  "));
+        tree.set_root(1);
+
+        tree.append_child(1, 2);
+        tree.append_child(2, 3);
+        tree.append_child(1, 4);
+        tree.append_child(1, 5);
+        tree.append_child(1, 6);
+        tree.append_child(6, 7);
+
+        tree
+    }
+
+    #[test]
+    fn test_normalize_default() {
+        let mut tree = create_tree();
+
+        let stats = normalize_tree(&mut tree, &NormalizeOptions::default());
+
+        // SCRIPT 子树（2 个节点）+ 纯空白文本节点
+        assert_eq!(stats.removed_nodes, 3);
+        assert_eq!(stats.collapsed_texts, 1);
+        assert_eq!(tree.node_count(), 4);
+
+        let root = tree.get_node(1).unwrap();
+        assert_eq!(root.tag_name.as_deref(), Some("div"));
+        assert_eq!(root.children, vec![4, 6]);
+        assert_eq!(root.attributes[0].0, "class");
+        assert_eq!(root.attributes[1].0, "id");
+
+        assert_eq!(tree.get_node(4).unwrap().text_content.as_deref(), Some("hello world"));
+        assert_eq!(tree.get_node(7).unwrap().text_content.as_deref(), Some("  keep  "));
+    }
+
+    #[test]
+    fn test_normalize_disabled() {
+        let mut tree = create_tree();
+        let options = NormalizeOptions {
+            strip_scripts: false,
+            collapse_whitespace: false,
+            sort_attributes: false,
+            lowercase_tags: false,
+            base_url: None,
+        };
+
+        let stats = normalize_tree(&mut tree, &options);
+
+        assert_eq!(stats, NormalizeStats::default());
+        assert_eq!(tree.node_count(), 7);
+        assert_eq!(tree.get_node(1).unwrap().tag_name.as_deref(), Some("DIV"));
+    }
+
+    #[test]
+    fn test_normalize_resolves_urls() {
+        let mut tree = DomTree::new();
+        tree.add_node(DomNode::new_element(1, "a").with_attr("href", "../about.html"));
+        tree.add_node(DomNode::new_element(2, "img").with_attr("src", "https://cdn.example.com/x.png"));
+        tree.add_node(DomNode::new_element(3, "div").with_attr("title", "docs/index.html"));
+        tree.set_root(1);
+        tree.append_child(1, 2);
+        tree.append_child(1, 3);
+
+        let options = NormalizeOptions::default().with_base_url("https://example.com/blog/post.html");
+        let stats = normalize_tree(&mut tree, &options);
+
+        assert_eq!(stats.resolved_urls, 1);
+        assert_eq!(tree.get_node(1).unwrap().get_attr("href"), Some("https://example.com/about.html"));
+        assert_eq!(tree.get_node(2).unwrap().get_attr("src"), Some("https://cdn.example.com/x.png"));
+        assert_eq!(tree.get_node(3).unwrap().get_attr("title"), Some("docs/index.html"));
+    }
+
+    #[test]
+    fn test_resolve_url() {
+        let base = "https://example.com/a/b/page.html?x=1#top";
+
+        assert_eq!(resolve_url(base, "c.html").as_deref(), Some("https://example.com/a/b/c.html"));
+        assert_eq!(resolve_url(base, "./c/").as_deref(), Some("https://example.com/a/b/c/"));
+        assert_eq!(resolve_url(base, "../../../c").as_deref(), Some("https://example.com/c"));
+        assert_eq!(resolve_url(base, "/root?q=2").as_deref(), Some("https://example.com/root?q=2"));
+        assert_eq!(resolve_url(base, "//cdn.example.com/x").as_deref(), Some("https://cdn.example.com/x"));
+        assert_eq!(resolve_url(base, "?y=2").as_deref(), Some("https://example.com/a/b/page.html?y=2"));
+        assert_eq!(resolve_url(base, "#bottom").as_deref(), Some("https://example.com/a/b/page.html?x=1#bottom"));
+        assert_eq!(resolve_url("https://example.com", "c").as_deref(), Some("https://example.com/c"));
+
+        assert_eq!(resolve_url(base, "mailto:a@example.com"), None);
+        assert_eq!(resolve_url(base, ""), None);
+        assert_eq!(resolve_url("about:blank", "c.html"), None);
+    }
+
+    #[test]
+    fn test_normalized_diff_ignores_serializer_noise() {
+        let mut old = DomTree::new();
+        old.add_node(DomNode::new_element(1, "DIV").with_attr("id", "main").with_attr("class", "box"));
+        old.add_node(DomNode::new_text(2, "hello  world"));
+        old.set_root(1);
+        old.append_child(1, 2);
+
+        let mut new = DomTree::new();
+        new.add_node(DomNode::new_element(1, "div").with_attr("class", "box").with_attr("id", "main"));
+        new.add_node(DomNode::new_text(2, "hello world"));
+        new.set_root(1);
+        new.append_child(1, 2);
+
+        assert!(compute_tree_diff(&old, &new).has_changes());
+        for algorithm in [DiffAlgorithm::Heuristic, DiffAlgorithm::TreeEditDistance] {
+            let report = compute_normalized_diff(&old, &new, &NormalizeOptions::default(), &DiffOptions::new(algorithm)).unwrap();
+            assert_eq!(report.algorithm, algorithm);
+            assert!(!report.diff.has_changes());
+        }
+    }
+
+    #[test]
+    fn test_collapse_skips_nested_preserved_text() {
+        // div > pre > span > "  a  "，以及 pre 之后的兄弟文本 "  b  "
+        let mut tree = DomTree::new();
+        tree.add_node(DomNode::new_element(1, "div"));
+        tree.add_node(DomNode::new_element(2, "PRE"));
+        tree.add_node(DomNode::new_element(3, "span"));
+        tree.add_node(DomNode::new_text(4, "  a  "));
+        tree.add_node(DomNode::new_text(5, "  b  "));
+        tree.set_root(1);
+        tree.append_child(1, 2);
+        tree.append_child(2, 3);
+        tree.append_child(3, 4);
+        tree.append_child(1, 5);
+
+        let stats = normalize_tree(&mut tree, &NormalizeOptions::default());
+
+        assert_eq!(stats.collapsed_texts, 1);
+        assert_eq!(tree.get_node(4).unwrap().text_content.as_deref(), Some("  a  "));
+        assert_eq!(tree.get_node(5).unwrap().text_content.as_deref(), Some("b"));
+    }
+}
@@ -260,7 +260,7 @@ fn build_children_index(tree: &DomTree) -> HashMap<NodeId, Vec<NodeId>> {
 }
 
 /// 比较两个节点的属性（O(1)）
-fn compare_nodes(old: &DomNode, new: &DomNode, diff: &mut TreeDiff) {
+pub(crate) fn compare_nodes(old: &DomNode, new: &DomNode, diff: &mut TreeDiff) {
     let mut changes = Vec::new();
 
     // 比较属性
@@ -11,6 +11,10 @@
 
 use std::collections::HashMap;
 
+pub mod truncate;
+
+pub use truncate::{TruncateOptions, TruncationReport, truncate_tree};
+
 /// 节点 ID（64 位，支持 2^64 个节点）
 pub type NodeId = u64;
 
@@ -350,6 +354,34 @@ impl DomTree {
         true
     }
 
+    /// 移除整棵子树（从父节点摘除并释放所有后代节点）
+    ///
+    /// 返回被移除的节点数量，节点不存在时返回 0。
+    pub fn remove_subtree(&mut self, root_id: NodeId) -> usize {
+        let Some(parent_id) = self.nodes.get(&root_id).map(|node| node.parent) else {
+            return 0;
+        };
+
+        if let Some(parent_id) = parent_id {
+            self.remove_child(parent_id, root_id);
+        }
+        if self.root_id == Some(root_id) {
+            self.root_id = None;
+        }
+
+        // 使用显式栈释放后代节点（避免递归）
+        let mut removed = 0;
+        let mut stack = vec![root_id];
+        while let Some(id) = stack.pop() {
+            if let Some(node) = self.nodes.remove(&id) {
+                stack.extend(node.children);
+                removed += 1;
+            }
+        }
+
+        removed
+    }
+
     /// 克隆子树（深拷贝，用于测试）
     pub fn clone_subtree(&mut self, root_id: NodeId) -> Option<NodeId> {
         let root = self.get_node(root_id)?.clone();
@@ -515,4 +547,29 @@ mod tests {
         let ids: Vec<_> = tree.iter().collect();
         assert_eq!(ids, vec![1, 2, 4, 5, 3]);
     }
+
+    #[test]
+    fn test_remove_subtree() {
+        let mut tree = DomTree::new();
+
+        tree.add_node(DomNode::new_element(1, "div"));
+        tree.add_node(DomNode::new_element(2, "span"));
+        tree.add_node(DomNode::new_element(3, "p"));
+        tree.add_node(DomNode::new_text(4, "hello"));
+        tree.set_root(1);
+
+        tree.append_child(1, 2);
+        tree.append_child(1, 3);
+        tree.append_child(2, 4);
+
+        assert_eq!(tree.remove_subtree(2), 2);
+        assert_eq!(tree.node_count(), 2);
+        assert!(tree.get_node(4).is_none());
+
+        let root = tree.get_node(1).unwrap();
+        assert_eq!(root.children, vec![3]);
+        assert_eq!(tree.get_node(3).unwrap().prev_sibling, None);
+
+        assert_eq!(tree.remove_subtree(42), 0);
+    }
 }
@@ -0,0 +1,566 @@
+//! # 超大页面截断策略
+//!
+//! 捕获结果超过节点数/字节数上限时，按固定策略截断 DOM 树，而不是直接失败，
+//! 并生成 [`TruncationReport`] 随快照元数据一起保存，使差分结果仍然有意义。
+//!
+//! ## 截断顺序
+//!
+//! 依次执行以下阶段，每个阶段后重新检查上限，满足后立即停止：
+//!
+//! 1. **长文本截断**：文本/注释超过 N 个字符时截断
+//! 2. **深度上限**：超过最大深度的子树整体移除
+//! 3. **子树节点上限**：任意深度超过 N 个节点的子树（如巨型表格）按前序裁剪，
+//!    从最大的子树开始，只裁剪到满足节点数上限为止
+//! 4. **总量兜底**：仍超过节点数/字节数上限时，按前序保留前缀
+//!
+//! 前序遍历的前缀总是连通的（祖先先于后代），因此截断后仍是一棵合法的树。
+
+use super::{DomNode, DomTree, NodeId};
+use std::collections::HashMap;
+
+/// 截断配置
+#[derive(Debug, Clone, Copy, PartialEq, Eq)]
+#[cfg_attr(feature = "serde", derive(serde::Serialize, serde::Deserialize))]
+pub struct TruncateOptions {
+    /// 触发截断的节点数上限
+    pub max_nodes: usize,
+    /// 触发截断的字节数上限（估算值，见 [`estimated_bytes`]）
+    pub max_bytes: usize,
+    /// 最大深度（根节点深度为 0）
+    pub max_depth: Option<usize>,
+    /// 单个子树（根节点除外）保留的最大节点数
+    pub max_subtree_nodes: Option<usize>,
+    /// 文本/注释保留的最大字符数
+    pub max_text_chars: Option<usize>,
+}
+
+impl Default for TruncateOptions {
+    fn default() -> Self {
+        Self {
+            max_nodes: 100_000,
+            max_bytes: 5 * 1024 * 1024,
+            max_depth: Some(64),
+            max_subtree_nodes: Some(20_000),
+            max_text_chars: Some(4_096),
+        }
+    }
+}
+
+/// 截断报告（写入快照元数据）
+#[derive(Debug, Clone, Copy, Default, PartialEq, Eq)]
+#[cfg_attr(feature = "serde", derive(serde::Serialize, serde::Deserialize))]
+pub struct TruncationReport {
+    /// 截断前节点数
+    pub original_nodes: usize,
+    /// 截断前字节数（估算）
+    pub original_bytes: usize,
+    /// 截断后节点数
+    pub retained_nodes: usize,
+    /// 截断后字节数（估算）
+    pub retained_bytes: usize,
+    /// 被截断的文本节点数
+    pub truncated_texts: usize,
+    /// 因深度上限移除的节点数
+    pub depth_pruned_nodes: usize,
+    /// 因子树节点上限移除的节点数
+    pub subtree_pruned_nodes: usize,
+    /// 因总量兜底移除的节点数
+    pub limit_pruned_nodes: usize,
+}
+
+impl TruncationReport {
+    /// 是否发生了截断
+    #[must_use]
+    pub fn is_truncated(&self) -> bool {
+        self.truncated_texts > 0 || self.retained_nodes < self.original_nodes
+    }
+}
+
+/// 估算单个节点的序列化字节数（标签名 + 文本 + 属性名/值）
+#[must_use]
+pub fn node_bytes(node: &DomNode) -> usize {
+    let tag = node.tag_name.as_ref().map_or(0, String::len);
+    let text = node.text_content.as_ref().map_or(0, String::len);
+    let attrs: usize = node.attributes.iter().map(|(name, value)| name.len() + value.len()).sum();
+
+    tag + text + attrs
+}
+
+/// 估算整棵树的序列化字节数
+#[must_use]
+pub fn estimated_bytes(tree: &DomTree) -> usize {
+    tree.iter().filter_map(|id| tree.get_node(id)).map(node_bytes).sum()
+}
+
+/// 超过上限时按策略截断 DOM 树（原地修改）
+///
+/// 未超过上限时不做任何修改，报告中 `is_truncated()` 为 `false`。
+pub fn truncate_tree(tree: &mut DomTree, options: &TruncateOptions) -> TruncationReport {
+    let original_bytes = estimated_bytes(tree);
+    let mut report = TruncationReport {
+        original_nodes: tree.node_count(),
+        original_bytes,
+        ..TruncationReport::default()
+    };
+
+    let Some(root) = tree.root() else {
+        return finish(tree, report);
+    };
+    if tree.node_count() <= options.max_nodes && original_bytes <= options.max_bytes {
+        return finish(tree, report);
+    }
+
+    if let Some(max_chars) = options.max_text_chars {
+        report.truncated_texts = truncate_texts(tree, max_chars);
+        if fits(tree, options) {
+            return finish(tree, report);
+        }
+    }
+
+    if let Some(max_depth) = options.max_depth {
+        report.depth_pruned_nodes = prune_depth(tree, root, max_depth);
+        if fits(tree, options) {
+            return finish(tree, report);
+        }
+    }
+
+    if let Some(max_subtree_nodes) = options.max_subtree_nodes {
+        report.subtree_pruned_nodes = prune_subtrees(tree, root, max_subtree_nodes, options);
+        if fits(tree, options) {
+            return finish(tree, report);
+        }
+    }
+
+    let (mut kept_nodes, mut kept_bytes) = (0, 0);
+    report.limit_pruned_nodes = prune_preorder(tree, root, |node| {
+        kept_nodes += 1;
+        kept_bytes += node_bytes(node);
+        kept_nodes <= options.max_nodes && kept_bytes <= options.max_bytes
+    });
+
+    finish(tree, report)
+}
+
+/// 是否满足节点数/字节数上限
+fn fits(tree: &DomTree, options: &TruncateOptions) -> bool {
+    tree.node_count() <= options.max_nodes && estimated_bytes(tree) <= options.max_bytes
+}
+
+/// 填写截断后的统计
+fn finish(tree: &DomTree, mut report: TruncationReport) -> TruncationReport {
+    report.retained_nodes = tree.node_count();
+    report.retained_bytes = estimated_bytes(tree);
+    report
+}
+
+/// 截断超长文本，返回被截断的节点数
+fn truncate_texts(tree: &mut DomTree, max_chars: usize) -> usize {
+    let ids: Vec<NodeId> = tree.iter().collect();
+    let mut truncated = 0;
+
+    for id in ids {
+        let Some(text) = tree.get_node_mut(id).and_then(|node| node.text_content.as_mut()) else {
+            continue;
+        };
+        if let Some((byte_index, _)) = text.char_indices().nth(max_chars) {
+            text.truncate(byte_index);
+            truncated += 1;
+        }
+    }
+
+    truncated
+}
+
+/// 移除超过最大深度的子树，返回移除的节点数
+fn prune_depth(tree: &mut DomTree, root: NodeId, max_depth: usize) -> usize {
+    let mut to_remove = Vec::new();
+    let mut stack = vec![(root, 0)];
+
+    while let Some((id, depth)) = stack.pop() {
+        let Some(node) = tree.get_node(id) else {
+            continue;
+        };
+        if depth == max_depth {
+            to_remove.extend_from_slice(&node.children);
+        } else {
+            stack.extend(node.children.iter().map(|&child| (child, depth + 1)));
+        }
+    }
+
+    to_remove.into_iter().map(|id| tree.remove_subtree(id)).sum()
+}
+
+/// 裁剪超过节点上限的子树（根节点除外），返回移除的节点数
+///
+/// 每轮找出最小的超限子树（自身超限但子节点均未超限），从大到小裁剪：
+/// 只超过节点数上限时裁剪到刚好满足（但不少于 `max_subtree_nodes`），
+/// 超过字节数上限时裁剪到 `max_subtree_nodes`。每次裁剪后重新检查上限，满足即停止；
+/// 裁剪后祖先仍超限的，在下一轮处理。
+fn prune_subtrees(tree: &mut DomTree, root: NodeId, max_subtree_nodes: usize, options: &TruncateOptions) -> usize {
+    let mut pruned = 0;
+
+    while !fits(tree, options) {
+        let sizes = subtree_sizes(tree);
+        let mut oversized: Vec<(usize, NodeId)> = sizes
+            .iter()
+            .filter(|&(&id, &size)| id != root && size > max_subtree_nodes)
+            .filter(|&(&id, _)| {
+                tree.get_node(id).is_some_and(|node| {
+                    node.children.iter().all(|child| sizes.get(child).is_none_or(|&size| size <= max_subtree_nodes))
+                })
+            })
+            .map(|(&id, &size)| (size, id))
+            .collect();
+        if oversized.is_empty() {
+            break;
+        }
+        oversized.sort_unstable_by(|a, b| b.0.cmp(&a.0).then(a.1.cmp(&b.1)));
+
+        let mut bytes = estimated_bytes(tree);
+        for (size, id) in oversized {
+            let excess = tree.node_count().saturating_sub(options.max_nodes);
+            let bytes_exceeded = bytes > options.max_bytes;
+            if excess == 0 && !bytes_exceeded {
+                break;
+            }
+
+            let limit = if bytes_exceeded {
+                max_subtree_nodes
+            } else {
+                max_subtree_nodes.max(size.saturating_sub(excess))
+            };
+            let bytes_before = subtree_bytes(tree, id);
+            let mut kept = 0;
+            pruned += prune_preorder(tree, id, |_| {
+                kept += 1;
+                kept <= limit
+            });
+            bytes -= bytes_before - subtree_bytes(tree, id);
+        }
+    }
+
+    pruned
+}
+
+/// 估算子树的序列化字节数
+fn subtree_bytes(tree: &DomTree, root: NodeId) -> usize {
+    let mut bytes = 0;
+    let mut stack = vec![root];
+
+    while let Some(id) = stack.pop() {
+        let Some(node) = tree.get_node(id) else {
+            continue;
+        };
+        bytes += node_bytes(node);
+        stack.extend_from_slice(&node.children);
+    }
+
+    bytes
+}
+
+/// 计算每个节点的子树节点数（含自身）
+fn subtree_sizes(tree: &DomTree) -> HashMap<NodeId, usize> {
+    let order: Vec<NodeId> = tree.iter().collect();
+    let mut sizes = HashMap::with_capacity(order.len());
+
+    // 逆前序保证子节点先于父节点
+    for &id in order.iter().rev() {
+        let Some(node) = tree.get_node(id) else {
+            continue;
+        };
+        let size = 1 + node.children.iter().filter_map(|child| sizes.get(child)).sum::<usize>();
+        sizes.insert(id, size);
+    }
+
+    sizes
+}
+
+/// 按前序遍历子树，`keep` 返回 `false` 的节点连同后代一起移除，返回移除的节点数
+fn prune_preorder(tree: &mut DomTree, root: NodeId, mut keep: impl FnMut(&DomNode) -> bool) -> usize {
+    let mut to_remove = Vec::new();
+    let mut stack = vec![root];
+
+    while let Some(id) = stack.pop() {
+        let Some(node) = tree.get_node(id) else {
+            continue;
+        };
+        if keep(node) {
+            stack.extend(node.children.iter().rev());
+        } else {
+            to_remove.push(id);
+        }
+    }
+
+    to_remove.into_iter().map(|id| tree.remove_subtree(id)).sum()
+}
+
+#[cfg(test)]
+mod tests {
+    use super::*;
+
+    /// 构建树：
+    ///
+    /// ```text
+    /// body
+    /// ├── table
+    /// │   ├── tr > "row 0"
+    /// │   ├── ...
+    /// │   └── tr > "row 9"
+    /// └── p > "0123456789"
+    /// ```
+    fn create_tree() -> DomTree {
+        let mut tree = DomTree::new();
+
+        tree.add_node(DomNode::new_element(1, "body"));
+        tree.add_node(DomNode::new_element(2, "table"));
+        tree.set_root(1);
+        tree.append_child(1, 2);
+
+        for row in 0..10 {
+            let tr = 10 + row * 2;
+            tree.add_node(DomNode::new_element(tr, "tr"));
+            tree.add_node(DomNode::new_text(tr + 1, format!("row {row}")));
+            tree.append_child(2, tr);
+            tree.append_child(tr, tr + 1);
+        }
+
+        tree.add_node(DomNode::new_element(3, "p"));
+        tree.add_node(DomNode::new_text(4, "0123456789"));
+        tree.append_child(1, 3);
+        tree.append_child(3, 4);
+
+        tree
+    }
+
+    fn no_strategy(max_nodes: usize) -> TruncateOptions {
+        TruncateOptions {
+            max_nodes,
+            max_bytes: usize::MAX,
+            max_depth: None,
+            max_subtree_nodes: None,
+            max_text_chars: None,
+        }
+    }
+
+    #[test]
+    fn test_within_limits_untouched() {
+        let mut tree = create_tree();
+
+        let report = truncate_tree(&mut tree, &TruncateOptions::default());
+
+        assert!(!report.is_truncated());
+        assert_eq!(report.original_nodes, 24);
+        assert_eq!(report.retained_nodes, 24);
+        assert_eq!(report.original_bytes, report.retained_bytes);
+    }
+
+    #[test]
+    fn test_truncate_texts() {
+        let mut tree = create_tree();
+        // 90 字节触发截断，文本截断后为 74 字节，无需兜底
+        let options = TruncateOptions {
+            max_bytes: 80,
+            max_text_chars: Some(4),
+            ..no_strategy(usize::MAX)
+        };
+
+        let report = truncate_tree(&mut tree, &options);
+
+        assert_eq!(report.original_bytes, 90);
+        assert_eq!(report.retained_bytes, 74);
+        assert_eq!(report.truncated_texts, 11);
+        assert_eq!(report.retained_nodes, 24);
+        assert_eq!(tree.get_node(4).unwrap().text_content.as_deref(), Some("0123"));
+    }
+
+    #[test]
+    fn test_truncate_text_char_boundary() {
+        let mut tree = DomTree::new();
+        tree.add_node(DomNode::new_text(1, "你好世界"));
+        tree.set_root(1);
+
+        assert_eq!(truncate_texts(&mut tree, 2), 1);
+        assert_eq!(tree.get_node(1).unwrap().text_content.as_deref(), Some("你好"));
+    }
+
+    #[test]
+    fn test_depth_cap() {
+        let mut tree = create_tree();
+        let options = TruncateOptions {
+            max_depth: Some(1),
+            ..no_strategy(10)
+        };
+
+        let report = truncate_tree(&mut tree, &options);
+
+        // table 下 20 个节点 + p 下 1 个文本节点
+        assert_eq!(report.depth_pruned_nodes, 21);
+        assert_eq!(report.retained_nodes, 3);
+        assert!(tree.get_node(2).unwrap().children.is_empty());
+    }
+
+    #[test]
+    fn test_subtree_cap_keeps_siblings() {
+        let mut tree = create_tree();
+        let options = TruncateOptions {
+            max_subtree_nodes: Some(5),
+            ..no_strategy(10)
+        };
+
+        let report = truncate_tree(&mut tree, &options);
+
+        // table 子树只裁剪到满足节点数上限：table + 3 行（7 个节点），p 子树不受影响
+        assert_eq!(report.subtree_pruned_nodes, 14);
+        assert_eq!(report.limit_pruned_nodes, 0);
+        assert_eq!(report.retained_nodes, 10);
+        assert_eq!(tree.get_node(2).unwrap().children, vec![10, 12, 14]);
+        assert!(tree.get_node(4).is_some());
+    }
+
+    #[test]
+    fn test_subtree_cap_at_depth() {
+        // html > body > main > [table > 30 × tr > text, p > text]
+        let mut tree = DomTree::new();
+        for (id, tag) in [(1, "html"), (2, "body"), (3, "main"), (4, "table"), (5, "p")] {
+            tree.add_node(DomNode::new_element(id, tag));
+        }
+        tree.add_node(DomNode::new_text(6, "summary"));
+        tree.set_root(1);
+        tree.append_child(1, 2);
+        tree.append_child(2, 3);
+        tree.append_child(3, 4);
+        tree.append_child(3, 5);
+        tree.append_child(5, 6);
+        for row in 0..30 {
+            let tr = 10 + row * 2;
+            tree.add_node(DomNode::new_element(tr, "tr"));
+            tree.add_node(DomNode::new_text(tr + 1, format!("row {row}")));
+            tree.append_child(4, tr);
+            tree.append_child(tr, tr + 1);
+        }
+        let options = TruncateOptions {
+            max_subtree_nodes: Some(10),
+            ..no_strategy(20)
+        };
+
+        let report = truncate_tree(&mut tree, &options);
+
+        // 只裁剪深层的 table（61 → 15 个节点），其兄弟 p 保留
+        assert_eq!(report.original_nodes, 66);
+        assert_eq!(report.subtree_pruned_nodes, 46);
+        assert_eq!(report.limit_pruned_nodes, 0);
+        assert_eq!(report.retained_nodes, 20);
+        assert_eq!(tree.get_node(4).unwrap().children.len(), 7);
+        assert_eq!(tree.get_node(6).unwrap().text_content.as_deref(), Some("summary"));
+    }
+
+    #[test]
+    fn test_subtree_cap_stops_at_byte_limit() {
+        // body > [ul > 30 × "item NN", ul > 30 × "item NN"]，共 428 字节
+        let mut tree = DomTree::new();
+        tree.add_node(DomNode::new_element(1, "body"));
+        tree.set_root(1);
+        for ul in [2, 3] {
+            tree.add_node(DomNode::new_element(ul, "ul"));
+            tree.append_child(1, ul);
+            for item in 0..30 {
+                let id = ul * 100 + item;
+                tree.add_node(DomNode::new_text(id, format!("item {item:02}")));
+                tree.append_child(ul, id);
+            }
+        }
+        let options = TruncateOptions {
+            max_bytes: 328,
+            max_subtree_nodes: Some(10),
+            ..no_strategy(usize::MAX)
+        };
+
+        let report = truncate_tree(&mut tree, &options);
+
+        // 裁剪第一个列表（移除 21 个 7 字节文本）后已满足上限，第二个列表保留
+        assert_eq!(report.original_bytes, 428);
+        assert_eq!(report.subtree_pruned_nodes, 21);
+        assert_eq!(report.limit_pruned_nodes, 0);
+        assert_eq!(report.retained_bytes, 281);
+        assert_eq!(tree.get_node(2).unwrap().children.len(), 9);
+        assert_eq!(tree.get_node(3).unwrap().children.len(), 30);
+    }
+
+    #[test]
+    fn test_page_just_over_node_limit() {
+        // html > [head > title > "title", body > 49_998 × (p > text)]，共 100_001 个节点
+        let mut tree = DomTree::new();
+        for (id, tag) in [(1, "html"), (2, "head"), (3, "title"), (5, "body")] {
+            tree.add_node(DomNode::new_element(id, tag));
+        }
+        tree.add_node(DomNode::new_text(4, "title"));
+        tree.set_root(1);
+        tree.append_child(1, 2);
+        tree.append_child(2, 3);
+        tree.append_child(3, 4);
+        tree.append_child(1, 5);
+        for row in 0..49_998 {
+            let p = 10 + row * 2;
+            tree.add_node(DomNode::new_element(p, "p"));
+            tree.add_node(DomNode::new_text(p + 1, "text"));
+            tree.append_child(5, p);
+            tree.append_child(p, p + 1);
+        }
+
+        let report = truncate_tree(&mut tree, &TruncateOptions::default());
+
+        assert_eq!(report.original_nodes, 100_001);
+        assert_eq!(report.retained_nodes, 100_000);
+        assert_eq!(report.subtree_pruned_nodes, 1);
+        assert_eq!(report.depth_pruned_nodes + report.limit_pruned_nodes, 0);
+        assert!(tree.get_node(4).is_some());
+    }
+
+    #[test]
+    fn test_stops_once_within_limits() {
+        let mut tree = create_tree();
+        // 文本截断后已满足字节数上限，不再执行深度上限
+        let options = TruncateOptions {
+            max_bytes: 80,
+            max_text_chars: Some(4),
+            max_depth: Some(1),
+            ..no_strategy(usize::MAX)
+        };
+
+        let report = truncate_tree(&mut tree, &options);
+
+        assert_eq!(report.truncated_texts, 11);
+        assert_eq!(report.depth_pruned_nodes, 0);
+        assert_eq!(report.retained_nodes, 24);
+    }
+
+    #[test]
+    fn test_node_limit_fallback() {
+        let mut tree = create_tree();
+
+        let report = truncate_tree(&mut tree, &no_strategy(6));
+
+        assert!(report.is_truncated());
+        assert_eq!(report.retained_nodes, 6);
+        assert_eq!(report.limit_pruned_nodes, 18);
+
+        // 保留的是前序前缀：body, table, tr, "row 0", tr, "row 1"
+        let ids: Vec<NodeId> = tree.iter().collect();
+        assert_eq!(ids, vec![1, 2, 10, 11, 12, 13]);
+    }
+
+    #[test]
+    fn test_byte_limit_fallback() {
+        let mut tree = create_tree();
+        let options = TruncateOptions {
+            max_bytes: 20,
+            ..no_strategy(usize::MAX)
+        };
+
+        let report = truncate_tree(&mut tree, &options);
+
+        assert!(report.original_bytes > 20);
+        assert!(report.retained_bytes <= 20);
+        assert!(report.is_truncated());
+    }
+}
@@ -22,9 +22,12 @@ pub mod monitoring;
 pub mod wasm;
 
 pub use dom::{DomNode, DomTree, NodeId, NodeType, DomIter};
+pub use dom::{TruncateOptions, TruncationReport, truncate_tree};
 pub use diff::{DomOp, OpsGenerator, MutationRecord, MutationType};
 pub use diff::{DiffChange, TreeDiff, compute_tree_diff};
 pub use diff::{hash_node, NodeHash};
+pub use diff::{NormalizeOptions, NormalizeStats, normalize_tree, compute_normalized_diff};
+pub use diff::{DiffAlgorithm, DiffOptions, DiffReport, DiffError, compute_diff};
 pub use arena::DomArena;
 pub use arena::ArenaStats;
 pub use memory::{MemoryMonitor, MemorySummary};
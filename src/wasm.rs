@@ -7,7 +7,9 @@ use std::sync::Mutex;
 use std::collections::HashMap;
 
 use crate::dom::{DomTree, DomNode, NodeType, NodeId};
-use crate::diff::compute_tree_diff;
+use crate::dom::{TruncateOptions, TruncationReport, truncate_tree};
+use crate::diff::{compute_tree_diff, compute_normalized_diff, NormalizeOptions};
+use crate::diff::{compute_diff, DiffAlgorithm, DiffError, DiffOptions, DiffReport};
 use crate::arena::DomArena;
 use crate::monitoring;
 
@@ -19,6 +21,8 @@ struct GlobalState {
     trees: Vec<Option<DomTree>>,
     arenas: Vec<Option<DomArena>>,
     string_pools: Vec<Option<crate::pool::ObjectPool<String>>>,
+    /// 每棵树最近一次截断的报告
+    truncation_reports: HashMap<u64, TruncationReport>,
     next_tree_id: u64,
     next_arena_id: u64,
     next_pool_id: u64,
@@ -30,6 +34,7 @@ impl GlobalState {
             trees: Vec::with_capacity(16),
             arenas: Vec::with_capacity(8),
             string_pools: Vec::with_capacity(8),
+            truncation_reports: HashMap::new(),
             next_tree_id: 1,
             next_arena_id: 1,
             next_pool_id: 1,
@@ -218,6 +223,74 @@ pub extern "C" fn dom_tree_node_count(tree_id: u64) -> u32 {
     tree.node_count() as u32
 }
 
+/// 节点是否仍在树中（截断后用于过滤 JS 侧的节点镜像）
+#[unsafe(no_mangle)]
+pub extern "C" fn dom_tree_has_node(tree_id: u64, node_id: u64) -> u32 {
+    let state = GLOBAL_STATE.lock().unwrap();
+    let Some(tree) = state.get_tree(tree_id) else {
+        return 0;
+    };
+    tree.get_node(node_id).is_some() as u32
+}
+
+/// 截断报告字段数（`dom_tree_truncation_field` 的下标范围）
+pub const TRUNCATION_REPORT_LEN: u32 = 8;
+
+/// 超过节点数/字节数上限时按默认策略截断DOM树
+///
+/// 截断报告保存在全局状态中（写入快照元数据），通过 [`dom_tree_truncation_field`] 读取。
+///
+/// 参数：
+/// - max_nodes/max_bytes: 上限，0 表示使用默认值
+///
+/// 返回值：1 表示成功，0 表示失败
+#[unsafe(no_mangle)]
+pub extern "C" fn dom_tree_truncate(tree_id: u64, max_nodes: u32, max_bytes: u32) -> u32 {
+    let mut state = GLOBAL_STATE.lock().unwrap();
+    let Some(tree) = state.get_tree_mut(tree_id) else {
+        return 0;
+    };
+
+    let defaults = TruncateOptions::default();
+    let options = TruncateOptions {
+        max_nodes: if max_nodes == 0 { defaults.max_nodes } else { max_nodes as usize },
+        max_bytes: if max_bytes == 0 { defaults.max_bytes } else { max_bytes as usize },
+        ..defaults
+    };
+    let report = truncate_tree(tree, &options);
+    state.truncation_reports.insert(tree_id, report);
+
+    1
+}
+
+/// 读取最近一次截断报告的字段
+///
+/// 参数：
+/// - index: 0 original_nodes，1 original_bytes，2 retained_nodes，3 retained_bytes，
+///   4 truncated_texts，5 depth_pruned_nodes，6 subtree_pruned_nodes，7 limit_pruned_nodes
+///
+/// 返回值：字段值（树未截断过或下标越界时为 0）
+#[unsafe(no_mangle)]
+pub extern "C" fn dom_tree_truncation_field(tree_id: u64, index: u32) -> u32 {
+    let state = GLOBAL_STATE.lock().unwrap();
+    let Some(report) = state.truncation_reports.get(&tree_id) else {
+        return 0;
+    };
+
+    let value = match index {
+        0 => report.original_nodes,
+        1 => report.original_bytes,
+        2 => report.retained_nodes,
+        3 => report.retained_bytes,
+        4 => report.truncated_texts,
+        5 => report.depth_pruned_nodes,
+        6 => report.subtree_pruned_nodes,
+        7 => report.limit_pruned_nodes,
+        _ => 0,
+    };
+    value as u32
+}
+
 #[unsafe(no_mangle)]
 pub extern "C" fn dom_tree_delete(tree_id: u64) -> u32 {
     let mut state = GLOBAL_STATE.lock().unwrap();
@@ -226,6 +299,7 @@ pub extern "C" fn dom_tree_delete(tree_id: u64) -> u32 {
     }
     
     state.trees[(tree_id - 1) as usize] = None;
+    state.truncation_reports.remove(&tree_id);
     1
 }
 
@@ -247,6 +321,155 @@ pub extern "C" fn diff_compute(tree1_id: u64, tree2_id: u64) -> u32 {
     changes.changes.len() as u32
 }
 
+/// 规范化开关：移除 script/style
+pub const NORMALIZE_STRIP_SCRIPTS: u32 = 1;
+/// 规范化开关：折叠空白
+pub const NORMALIZE_COLLAPSE_WHITESPACE: u32 = 1 << 1;
+/// 规范化开关：属性排序
+pub const NORMALIZE_SORT_ATTRIBUTES: u32 = 1 << 2;
+/// 规范化开关：标签小写
+pub const NORMALIZE_LOWERCASE_TAGS: u32 = 1 << 3;
+
+/// 差分状态码：参数无效（算法编号无效或树不存在）
+pub const DIFF_STATUS_INVALID: u32 = 0;
+/// 差分状态码：成功
+pub const DIFF_STATUS_OK: u32 = 1;
+/// 差分状态码：节点数超过算法上限
+pub const DIFF_STATUS_TOO_MANY_NODES: u32 = 2;
+/// 差分状态码：精确算法的森林距离单元数超过上限
+pub const DIFF_STATUS_TOO_EXPENSIVE: u32 = 3;
+
+/// 算法编号：0 自动选择，1 启发式，2 精确树编辑距离，3 仅文本
+fn diff_algorithm_from_code(code: u32) -> Option<DiffAlgorithm> {
+    match code {
+        0 => Some(DiffAlgorithm::Auto),
+        1 => Some(DiffAlgorithm::Heuristic),
+        2 => Some(DiffAlgorithm::TreeEditDistance),
+        3 => Some(DiffAlgorithm::TextOnly),
+        _ => None,
+    }
+}
+
+/// 差分算法对应的编号（[`diff_algorithm_from_code`] 的逆映射）
+fn diff_algorithm_code(algorithm: DiffAlgorithm) -> u32 {
+    match algorithm {
+        DiffAlgorithm::Auto => 0,
+        DiffAlgorithm::Heuristic => 1,
+        DiffAlgorithm::TreeEditDistance => 2,
+        DiffAlgorithm::TextOnly => 3,
+    }
+}
+
+/// 写出差分结果（变更数、实际使用的算法编号、耗时微秒），返回状态码
+fn write_diff_result(
+    result: Result<DiffReport, DiffError>,
+    out_changes: *mut u32,
+    out_algorithm: *mut u32,
+    out_elapsed_us: *mut u64,
+) -> u32 {
+    let report = match result {
+        Ok(report) => report,
+        Err(DiffError::TooManyNodes { .. }) => return DIFF_STATUS_TOO_MANY_NODES,
+        Err(DiffError::TooExpensive { .. }) => return DIFF_STATUS_TOO_EXPENSIVE,
+    };
+
+    unsafe {
+        if !out_changes.is_null() {
+            *out_changes = report.diff.changes.len() as u32;
+        }
+        if !out_algorithm.is_null() {
+            *out_algorithm = diff_algorithm_code(report.algorithm);
+        }
+        if !out_elapsed_us.is_null() {
+            *out_elapsed_us = report.elapsed.as_micros() as u64;
+        }
+    }
+
+    DIFF_STATUS_OK
+}
+
+/// 规范化后计算两棵DOM树的差分（不修改原树）
+///
+/// 参数：
+/// - flags: `NORMALIZE_*` 开关的按位或
+/// - base_url_ptr/base_url_len: 解析相对 URL 的页面地址（空指针或长度为 0 表示不解析）
+/// - algorithm: 算法编号，同 [`diff_compute_with_algorithm`]
+/// - out_changes/out_algorithm/out_elapsed_us: 同 [`diff_compute_with_algorithm`]
+///
+/// 返回值：`DIFF_STATUS_*` 状态码
+#[unsafe(no_mangle)]
+pub extern "C" fn diff_compute_normalized(
+    tree1_id: u64,
+    tree2_id: u64,
+    flags: u32,
+    base_url_ptr: *const u8,
+    base_url_len: usize,
+    algorithm: u32,
+    out_changes: *mut u32,
+    out_algorithm: *mut u32,
+    out_elapsed_us: *mut u64,
+) -> u32 {
+    let Some(algorithm) = diff_algorithm_from_code(algorithm) else {
+        return DIFF_STATUS_INVALID;
+    };
+
+    let base_url = if base_url_ptr.is_null() || base_url_len == 0 {
+        None
+    } else {
+        unsafe {
+            let slice = std::slice::from_raw_parts(base_url_ptr, base_url_len);
+            std::str::from_utf8(slice).ok().map(str::to_string)
+        }
+    };
+
+    let options = NormalizeOptions {
+        strip_scripts: flags & NORMALIZE_STRIP_SCRIPTS != 0,
+        collapse_whitespace: flags & NORMALIZE_COLLAPSE_WHITESPACE != 0,
+        sort_attributes: flags & NORMALIZE_SORT_ATTRIBUTES != 0,
+        lowercase_tags: flags & NORMALIZE_LOWERCASE_TAGS != 0,
+        base_url,
+    };
+
+    let state = GLOBAL_STATE.lock().unwrap();
+    let (Some(tree1), Some(tree2)) = (state.get_tree(tree1_id), state.get_tree(tree2_id)) else {
+        return DIFF_STATUS_INVALID;
+    };
+
+    let result = compute_normalized_diff(tree1, tree2, &options, &DiffOptions::new(algorithm));
+    write_diff_result(result, out_changes, out_algorithm, out_elapsed_us)
+}
+
+/// 使用指定算法计算两棵DOM树的差分
+///
+/// 参数：
+/// - algorithm: 0 自动选择，1 启发式，2 精确树编辑距离，3 仅文本
+/// - out_changes: 差分操作数量（可为空）
+/// - out_algorithm: 实际使用的算法编号，`Auto` 解析为具体算法（可为空）
+/// - out_elapsed_us: 计算耗时（微秒，可为空）
+///
+/// 返回值：`DIFF_STATUS_*` 状态码，仅 `DIFF_STATUS_OK` 时写出结果
+#[unsafe(no_mangle)]
+pub extern "C" fn diff_compute_with_algorithm(
+    tree1_id: u64,
+    tree2_id: u64,
+    algorithm: u32,
+    out_changes: *mut u32,
+    out_algorithm: *mut u32,
+    out_elapsed_us: *mut u64,
+) -> u32 {
+    let Some(algorithm) = diff_algorithm_from_code(algorithm) else {
+        return DIFF_STATUS_INVALID;
+    };
+
+    let state = GLOBAL_STATE.lock().unwrap();
+    let (Some(tree1), Some(tree2)) = (state.get_tree(tree1_id), state.get_tree(tree2_id)) else {
+        return DIFF_STATUS_INVALID;
+    };
+
+    let result = compute_diff(tree1, tree2, &DiffOptions::new(algorithm));
+    write_diff_result(result, out_changes, out_algorithm, out_elapsed_us)
+}
+
 // ============================================
 // Arena 分配器 API
 // ============================================